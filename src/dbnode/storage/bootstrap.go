@@ -21,8 +21,10 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -30,6 +32,7 @@ import (
 	"github.com/m3db/m3/src/dbnode/storage/bootstrap"
 	xerrors "github.com/m3db/m3/src/x/errors"
 	"github.com/m3db/m3/src/x/instrument"
+	"github.com/m3db/m3/src/x/retry"
 
 	"github.com/uber-go/tally"
 	"go.uber.org/zap"
@@ -68,13 +71,23 @@ type bootstrapManager struct {
 	database                    database
 	mediator                    databaseMediator
 	opts                        Options
+	bootstrapOpts               BootstrapManagerOptions
 	log                         *zap.Logger
 	nowFn                       clock.NowFn
 	processProvider             bootstrap.ProcessProvider
 	state                       BootstrapState
 	hasPending                  bool
 	status                      tally.Gauge
+	unbootstrappedShardGauges   map[string]tally.Gauge
+	namespaceFailureCounters    map[string]tally.Counter
 	lastBootstrapCompletionTime time.Time
+	lastBootstrapErr            error
+	retrier                     retry.Retrier
+	forceCh                     chan struct{}
+	listeners                   []*listenerWorker
+	closeCh                     chan struct{}
+	closeOnce                   sync.Once
+	postBootstrapFlushPolicy    PostBootstrapFlushPolicy
 }
 
 func newBootstrapManager(
@@ -83,15 +96,35 @@ func newBootstrapManager(
 	opts Options,
 ) databaseBootstrapManager {
 	scope := opts.InstrumentOptions().MetricsScope()
-	return &bootstrapManager{
-		database:        database,
-		mediator:        mediator,
-		opts:            opts,
-		log:             opts.InstrumentOptions().Logger(),
-		nowFn:           opts.ClockOptions().NowFn(),
-		processProvider: opts.BootstrapProcessProvider(),
-		status:          scope.Gauge("bootstrapped"),
+	bootstrapOpts := opts.BootstrapManagerOptions()
+	mgr := &bootstrapManager{
+		database:                  database,
+		mediator:                  mediator,
+		opts:                      opts,
+		bootstrapOpts:             bootstrapOpts,
+		log:                       opts.InstrumentOptions().Logger(),
+		nowFn:                     opts.ClockOptions().NowFn(),
+		processProvider:           opts.BootstrapProcessProvider(),
+		status:                    scope.Gauge("bootstrapped"),
+		unbootstrappedShardGauges: make(map[string]tally.Gauge),
+		namespaceFailureCounters:  make(map[string]tally.Counter),
+		retrier:                   retry.NewRetrier(bootstrapOpts.BootstrapRetryOptions()),
+		forceCh:                   make(chan struct{}, 1),
+		closeCh:                   make(chan struct{}),
+		postBootstrapFlushPolicy:  opts.PostBootstrapFlushPolicy(),
 	}
+	return mgr
+}
+
+// Close stops each registered listener's dispatch worker and any pending
+// post-bootstrap flush scheduled by the flush policy. RunPeriodicBootstrap is
+// stopped by cancelling its own ctx, not by Close. It is safe to call more
+// than once.
+func (m *bootstrapManager) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closeCh)
+	})
+	return nil
 }
 
 func (m *bootstrapManager) IsBootstrapped() bool {
@@ -106,6 +139,10 @@ func (m *bootstrapManager) LastBootstrapCompletionTime() (time.Time, bool) {
 }
 
 func (m *bootstrapManager) Bootstrap() error {
+	return m.BootstrapWithContext(context.Background())
+}
+
+func (m *bootstrapManager) BootstrapWithContext(ctx context.Context) error {
 	m.Lock()
 	switch m.state {
 	case Bootstrapping:
@@ -128,10 +165,12 @@ func (m *bootstrapManager) Bootstrap() error {
 	m.mediator.DisableFileOps()
 	defer m.mediator.EnableFileOps()
 
+	bootstrapStart := m.nowFn()
+
 	// Keep performing bootstraps until none pending
 	multiErr := xerrors.NewMultiError()
 	for {
-		err := m.bootstrap()
+		err := m.bootstrap(ctx)
 		if err != nil {
 			multiErr = multiErr.Add(err)
 		}
@@ -149,19 +188,42 @@ func (m *bootstrapManager) Bootstrap() error {
 		if !currPending {
 			break
 		}
-	}
 
-	// NB(xichen): in order for bootstrapped data to be flushed to disk, a tick
-	// needs to happen to drain the in-memory buffers and a consequent flush will
-	// flush all the data onto disk. However, this has shown to be too intensive
-	// to do immediately after bootstrap due to bootstrapping nodes simultaneously
-	// attempting to tick through their series and flushing data, adding significant
-	// load to the cluster. It turns out to be better to let ticking happen naturally
-	// on its own course so that the load of ticking and flushing is more spread out
-	// across the cluster.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			multiErr = multiErr.Add(ctxErr)
+			// The pending bootstrap we just queued up for ourselves above
+			// will never run now, so leave the manager in a consistent,
+			// non-bootstrapping state rather than stuck in Bootstrapping
+			// forever (which would wedge IsBootstrapped() and every future
+			// Bootstrap() call behind errBootstrapEnqueued).
+			m.Lock()
+			m.state = Bootstrapped
+			m.hasPending = false
+			m.Unlock()
+			break
+		}
+	}
 
 	m.lastBootstrapCompletionTime = m.nowFn()
-	return multiErr.FinalError()
+	err := multiErr.FinalError()
+	m.Lock()
+	m.lastBootstrapErr = err
+	m.Unlock()
+
+	if err == nil {
+		namespaces, nsErr := m.database.GetOwnedNamespaces()
+		if nsErr != nil {
+			m.log.Error("skipping post-bootstrap flush scheduling, "+
+				"failed to list owned namespaces", zap.Error(nsErr))
+		} else {
+			m.postBootstrapFlushPolicy.Schedule(namespaces, m.mediator, m.closeCh)
+		}
+	}
+
+	duration := m.nowFn().Sub(bootstrapStart)
+	m.dispatch(func(l BootstrapListener) { l.OnBootstrapComplete(err, duration) })
+
+	return err
 }
 
 func (m *bootstrapManager) Report() {
@@ -170,23 +232,275 @@ func (m *bootstrapManager) Report() {
 	} else {
 		m.status.Update(0)
 	}
+	m.reportUnbootstrappedShardsByNamespace()
+}
+
+// RunPeriodicBootstrap runs the background self-healing bootstrap loop until
+// ctx is cancelled. It is started by the mediator alongside the other
+// database background loops (e.g. ticking, flushing).
+func (m *bootstrapManager) RunPeriodicBootstrap(ctx context.Context) {
+	if !m.bootstrapOpts.PeriodicBootstrapEnabled() {
+		return
+	}
+
+	ticker := time.NewTicker(m.bootstrapOpts.PeriodicBootstrapInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.forceCh:
+			m.tryPeriodicBootstrap(ctx)
+		case <-ticker.C:
+			m.tryPeriodicBootstrap(ctx)
+		}
+	}
+}
+
+// Force triggers an immediate periodic bootstrap check, skipping the wait
+// for the next ticker interval. It is safe to call concurrently and is a
+// no-op if a check is already pending.
+func (m *bootstrapManager) Force() {
+	select {
+	case m.forceCh <- struct{}{}:
+	default:
+		// A forced check is already pending.
+	}
+}
+
+func (m *bootstrapManager) tryPeriodicBootstrap(ctx context.Context) {
+	if !m.shouldBootstrap() {
+		return
+	}
+
+	attemptErr := m.retrier.Attempt(func() error {
+		err := m.BootstrapWithContext(ctx)
+		if err == errBootstrapEnqueued {
+			// Not a failure, a bootstrap is already in flight and will pick
+			// up any newly unbootstrapped shards.
+			return nil
+		}
+		return err
+	})
+	if attemptErr != nil {
+		m.log.Error("periodic bootstrap failed, will retry with backoff",
+			zap.Error(attemptErr))
+	}
+}
+
+// shouldBootstrap returns true if the fraction of bootstrapped shards across
+// owned namespaces has dropped below the configured threshold, or if the
+// last bootstrap attempt failed.
+func (m *bootstrapManager) shouldBootstrap() bool {
+	m.RLock()
+	lastErr := m.lastBootstrapErr
+	m.RUnlock()
+	if lastErr != nil {
+		return true
+	}
+
+	ratio, ok := m.bootstrappedShardRatio()
+	if !ok {
+		return false
+	}
+	return ratio < m.bootstrapOpts.MinBootstrappedShardRatio()
+}
+
+// bootstrappedShardRatio returns the fraction of owned shards (across all
+// owned namespaces) that are currently bootstrapped.
+func (m *bootstrapManager) bootstrappedShardRatio() (float64, bool) {
+	namespaces, err := m.database.GetOwnedNamespaces()
+	if err != nil {
+		return 0, false
+	}
+
+	var total, bootstrapped int
+	for _, namespace := range namespaces {
+		for _, shard := range namespace.GetOwnedShards() {
+			total++
+			if shard.IsBootstrapped() {
+				bootstrapped++
+			}
+		}
+	}
+	if total == 0 {
+		return 1, true
+	}
+	return float64(bootstrapped) / float64(total), true
+}
+
+// reportUnbootstrappedShardsByNamespace updates the per-namespace
+// "unbootstrapped-shards" gauge so operators can see which namespace is
+// lagging, rather than just a cluster-wide total.
+func (m *bootstrapManager) reportUnbootstrappedShardsByNamespace() {
+	namespaces, err := m.database.GetOwnedNamespaces()
+	if err != nil {
+		return
+	}
+
+	for _, namespace := range namespaces {
+		unbootstrapped := 0
+		for _, shard := range namespace.GetOwnedShards() {
+			if !shard.IsBootstrapped() {
+				unbootstrapped++
+			}
+		}
+		m.unbootstrappedShardGauge(namespace.ID().String()).Update(float64(unbootstrapped))
+	}
+}
+
+// unbootstrappedShardGauge returns (creating and caching if necessary) the
+// tally gauge tracking unbootstrapped shards for a given namespace.
+func (m *bootstrapManager) unbootstrappedShardGauge(namespaceID string) tally.Gauge {
+	m.Lock()
+	defer m.Unlock()
+
+	if gauge, ok := m.unbootstrappedShardGauges[namespaceID]; ok {
+		return gauge
+	}
+
+	scope := m.opts.InstrumentOptions().MetricsScope()
+	gauge := scope.Tagged(map[string]string{
+		"namespace": namespaceID,
+	}).Gauge("unbootstrapped-shards")
+	m.unbootstrappedShardGauges[namespaceID] = gauge
+	return gauge
+}
+
+// bootstrap runs the bootstrap process, retrying only the namespaces that
+// fail (rather than the whole bootstrap) with backoff between attempts, until
+// each has been attempted MaxNamespaceBootstrapRetries times in total. A
+// namespace that is still failing once its attempts are exhausted is
+// surfaced as a hard error. A namespace
+// that succeeds on an earlier attempt is excluded from later attempts, the
+// same way an exhausted namespace is, so it is never re-run (and never
+// re-counted as a failure) for the remainder of this call.
+func (m *bootstrapManager) bootstrap(ctx context.Context) error {
+	attempts := make(map[string]int)
+	exhausted := make(map[string]struct{})
+	succeeded := make(map[string]struct{})
+	maxAttempts := m.bootstrapOpts.MaxNamespaceBootstrapRetries()
+	permanentErrs := xerrors.NewMultiError()
+	attemptNum := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		attemptNum++
+		exclude := make(map[string]struct{}, len(exhausted)+len(succeeded))
+		for id := range exhausted {
+			exclude[id] = struct{}{}
+		}
+		for id := range succeeded {
+			exclude[id] = struct{}{}
+		}
+
+		namespaceErrs, attemptedIDs, runErr := m.runBootstrapAttempt(ctx, attemptNum, exclude)
+		if runErr != nil && len(namespaceErrs) == 0 && len(attemptedIDs) == 0 {
+			// Nothing partial to salvage, e.g. failed to list namespaces.
+			return runErr
+		}
+
+		for _, id := range attemptedIDs {
+			if _, failed := namespaceErrs[id]; !failed {
+				succeeded[id] = struct{}{}
+			}
+		}
+
+		if len(namespaceErrs) == 0 {
+			m.log.Info("bootstrap success")
+			return permanentErrs.FinalError()
+		}
+
+		var retryIn time.Duration
+		needsRetry := false
+		for id, nsErr := range namespaceErrs {
+			attempts[id]++
+			m.namespaceFailureCounter(id).Inc(1)
+
+			if attempts[id] < maxAttempts {
+				needsRetry = true
+				namespaceRetryIn := backoffForAttempt(m.bootstrapOpts.BootstrapRetryOptions(), attempts[id])
+				if retryIn == 0 || namespaceRetryIn < retryIn {
+					// Wake up as soon as the earliest-ready namespace is due
+					// to be retried. Every namespace still failing is re-run
+					// on that next attempt, whether or not its own backoff
+					// has elapsed.
+					retryIn = namespaceRetryIn
+				}
+				m.log.Warn("namespace bootstrap failed, will retry",
+					zap.String("namespace", id),
+					zap.Int("attempt", attempts[id]),
+					zap.Int("maxAttempts", maxAttempts),
+					zap.Duration("retryIn", namespaceRetryIn),
+					zap.Error(nsErr))
+				continue
+			}
+
+			m.log.Error("namespace bootstrap failed, exhausted retries",
+				zap.String("namespace", id),
+				zap.Int("attempts", attempts[id]),
+				zap.Error(nsErr))
+			exhausted[id] = struct{}{}
+			permanentErrs = permanentErrs.Add(fmt.Errorf("namespace %s: %w", id, nsErr))
+		}
+
+		if !needsRetry {
+			// Every failing namespace this attempt was either resolved or
+			// just exhausted its retries; nothing left to wait on.
+			return permanentErrs.FinalError()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryIn):
+		}
+	}
 }
 
-func (m *bootstrapManager) bootstrap() error {
+// runBootstrapAttempt runs a single bootstrap attempt against every
+// not-yet-bootstrapped shard, excluding any namespace ID present in
+// excludeNamespaceIDs — namespaces that have either already surfaced a
+// permanent error (exhausted their retries) or already succeeded on an
+// earlier attempt within this bootstrap() call, neither of which should be
+// run again. It returns the set of namespaces (by ID) that failed to
+// bootstrap on this attempt, the full set of namespace IDs actually
+// attempted (so the caller can tell which of the non-failing ones
+// succeeded), and the error from process.Run itself, if any. A non-nil
+// process.Run error doesn't prevent namespaces present in a partial result
+// from being recorded as succeeded.
+func (m *bootstrapManager) runBootstrapAttempt(
+	ctx context.Context,
+	attemptNum int,
+	excludeNamespaceIDs map[string]struct{},
+) (map[string]error, []string, error) {
 	// NB(r): construct new instance of the bootstrap process to avoid
 	// state being kept around by bootstrappers.
-	process, err := m.processProvider.Provide()
+	process, err := m.processProvider.Provide(ctx)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	namespaces, err := m.database.GetOwnedNamespaces()
+	ownedNamespaces, err := m.database.GetOwnedNamespaces()
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+
+	namespaces := make([]databaseNamespace, 0, len(ownedNamespaces))
+	for _, namespace := range ownedNamespaces {
+		if _, ok := excludeNamespaceIDs[namespace.ID().String()]; ok {
+			continue
+		}
+		namespaces = append(namespaces, namespace)
 	}
 
 	uniqueShards := make(map[uint32]struct{})
 	targets := make([]bootstrap.ProcessNamespace, 0, len(namespaces))
+	namespaceIDs := make([]string, 0, len(namespaces))
 	for _, namespace := range namespaces {
 		namespaceShards := namespace.GetOwnedShards()
 		bootstrapShards := make([]uint32, 0, len(namespaceShards))
@@ -206,6 +520,10 @@ func (m *bootstrapManager) bootstrap() error {
 			Shards:          bootstrapShards,
 			DataAccumulator: accumulator,
 		})
+		namespaceIDs = append(namespaceIDs, namespace.ID().String())
+
+		id := namespace.ID().String()
+		m.dispatch(func(l BootstrapListener) { l.OnNamespaceStart(id, bootstrapShards) })
 	}
 
 	start := m.nowFn()
@@ -214,23 +532,32 @@ func (m *bootstrapManager) bootstrap() error {
 	}
 	m.log.Info("bootstrap started", logFields...)
 
+	m.dispatch(func(l BootstrapListener) {
+		l.OnBootstrapStart(BootstrapRun{
+			Attempt:    attemptNum,
+			Start:      start,
+			Namespaces: namespaceIDs,
+		})
+	})
+
 	// Run the bootstrap.
-	bootstrapResult, err := process.Run(start, targets)
+	bootstrapResult, runErr := process.Run(ctx, start, targets)
 
 	logFields = append(logFields,
 		zap.Duration("duration", m.nowFn().Sub(start)))
 
-	if err != nil {
-		m.log.Error("bootstrap failed",
-			append(logFields, zap.Error(err))...)
-		return err
+	if runErr != nil {
+		m.log.Error("bootstrap failed", append(logFields, zap.Error(runErr))...)
+		if bootstrapResult.Results.Len() == 0 {
+			// No partial result to salvage, fail every namespace.
+			return nil, nil, runErr
+		}
 	}
 
-	// Use a multi-error here because we want to at least bootstrap
-	// as many of the namespaces as possible.
-	multiErr := xerrors.NewMultiError()
+	namespaceErrs := make(map[string]error)
 	for _, namespace := range namespaces {
 		id := namespace.ID()
+		idStr := id.String()
 		result, ok := bootstrapResult.Results.Get(id)
 		if !ok {
 			err := fmt.Errorf("missing namespace from bootstrap result: %v",
@@ -240,20 +567,54 @@ func (m *bootstrapManager) bootstrap() error {
 				l.Error("bootstrap failed",
 					append(logFields, zap.Error(err))...)
 			})
-			return err
+			namespaceErrs[idStr] = err
+			m.dispatch(func(l BootstrapListener) {
+				l.OnNamespaceComplete(idStr, bootstrap.NamespaceResult{}, err)
+			})
+			continue
 		}
 
-		if err := namespace.Bootstrap(result); err != nil {
-			multiErr = multiErr.Add(err)
+		nsErr := namespace.Bootstrap(result)
+		if nsErr != nil {
+			namespaceErrs[idStr] = nsErr
 		}
+		m.dispatch(func(l BootstrapListener) { l.OnNamespaceComplete(idStr, result, nsErr) })
 	}
 
-	if err := multiErr.FinalError(); err != nil {
-		m.log.Info("bootstrap namespaces failed",
-			append(logFields, zap.Error(err))...)
-		return err
+	return namespaceErrs, namespaceIDs, runErr
+}
+
+// namespaceFailureCounter returns (creating and caching if necessary) the
+// tally counter tracking bootstrap attempt failures for a given namespace.
+func (m *bootstrapManager) namespaceFailureCounter(namespaceID string) tally.Counter {
+	m.Lock()
+	defer m.Unlock()
+
+	if counter, ok := m.namespaceFailureCounters[namespaceID]; ok {
+		return counter
 	}
 
-	m.log.Info("bootstrap success")
-	return nil
+	scope := m.opts.InstrumentOptions().MetricsScope()
+	counter := scope.Tagged(map[string]string{
+		"namespace": namespaceID,
+	}).Counter("bootstrap-namespace-failures")
+	m.namespaceFailureCounters[namespaceID] = counter
+	return counter
+}
+
+// backoffForAttempt computes an exponential backoff (with optional jitter)
+// for the given 1-indexed retry attempt.
+func backoffForAttempt(opts retry.Options, attempt int) time.Duration {
+	backoff := opts.InitialBackoff()
+	for i := 1; i < attempt; i++ {
+		backoff = time.Duration(float64(backoff) * opts.BackoffFactor())
+		if backoff >= opts.MaxBackoff() {
+			backoff = opts.MaxBackoff()
+			break
+		}
+	}
+	if opts.Jitter() {
+		backoff = time.Duration(rand.Int63n(int64(backoff) + 1))
+	}
+	return backoff
 }
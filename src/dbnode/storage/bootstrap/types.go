@@ -0,0 +1,108 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/m3db/m3/src/dbnode/namespace"
+	"github.com/m3db/m3/src/x/ident"
+)
+
+// ProcessProvider constructs a new Process for each bootstrap attempt, so
+// that no state is kept around between attempts by the underlying
+// bootstrappers.
+type ProcessProvider interface {
+	// Provide constructs a new Process. ctx is threaded through from the
+	// triggering databaseBootstrapManager.BootstrapWithContext call; a
+	// Provide implementation that does non-trivial setup work should
+	// respect ctx cancellation rather than blocking indefinitely.
+	Provide(ctx context.Context) (Process, error)
+}
+
+// Process runs a single bootstrap attempt across a set of namespaces.
+type Process interface {
+	// Run executes the bootstrap for the given namespaces starting at
+	// start. Implementations must check ctx between (and ideally within)
+	// individual bootstrapper steps so that a cancelled ctx - e.g. on
+	// graceful shutdown or reshard - aborts the in-flight attempt instead of
+	// running to completion regardless.
+	Run(ctx context.Context, start time.Time, namespaces []ProcessNamespace) (ProcessResult, error)
+}
+
+// ProcessNamespace is a namespace (and the subset of its shards that still
+// need bootstrapping) handed to a Process.
+type ProcessNamespace struct {
+	Metadata        namespace.Metadata
+	Shards          []uint32
+	DataAccumulator NamespaceDataAccumulator
+}
+
+// NamespaceDataAccumulator accumulates data a bootstrapper produces for a
+// namespace as the bootstrap runs.
+type NamespaceDataAccumulator interface {
+	// Close releases any resources held by the accumulator once the
+	// bootstrap attempt that owns it has finished with it.
+	Close() error
+}
+
+// ProcessResult is the outcome of a Process.Run call, including a (possibly
+// partial, if Run also returned a non-nil error) per-namespace result.
+type ProcessResult struct {
+	Results NamespaceResultsMap
+}
+
+// NamespaceResult is the per-namespace outcome of a bootstrap attempt.
+type NamespaceResult struct {
+	Shards          []uint32
+	ErrorsBootstrap []error
+}
+
+// NamespaceResultsMap maps a namespace ID to its NamespaceResult. It mirrors
+// the generated map types used elsewhere in M3 (keyed by the string form of
+// an ident.ID) so ProcessResult.Results can be queried by ident.ID directly.
+type NamespaceResultsMap struct {
+	entries map[string]NamespaceResult
+}
+
+// NewNamespaceResultsMap returns an empty NamespaceResultsMap.
+func NewNamespaceResultsMap() NamespaceResultsMap {
+	return NamespaceResultsMap{entries: make(map[string]NamespaceResult)}
+}
+
+// Get returns the result for id, if present.
+func (m NamespaceResultsMap) Get(id ident.ID) (NamespaceResult, bool) {
+	result, ok := m.entries[id.String()]
+	return result, ok
+}
+
+// Len returns the number of namespace results recorded so far. A zero-value
+// NamespaceResultsMap (e.g. one returned alongside a Process.Run error with
+// nothing to salvage) has a Len of 0.
+func (m NamespaceResultsMap) Len() int {
+	return len(m.entries)
+}
+
+// Set records the result for id.
+func (m NamespaceResultsMap) Set(id ident.ID, result NamespaceResult) {
+	m.entries[id.String()] = result
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// PostBootstrapFlushPolicy decides what, if anything, happens right after a
+// bootstrap completes. Ticking and flushing immediately after bootstrap
+// drains the in-memory buffers built up while bootstrapping, but doing so on
+// every node at once adds a significant, simultaneous load spike to the
+// cluster. A policy lets operators pick the tradeoff that suits their
+// cluster instead of always waiting for the next natural tick.
+type PostBootstrapFlushPolicy interface {
+	// Schedule is invoked once after a bootstrap completes successfully and
+	// lastBootstrapCompletionTime has been updated. namespaces are the
+	// namespaces that were just bootstrapped. Implementations that flush
+	// asynchronously must not block the caller, and must start no further
+	// flush once cancel is closed (e.g. on database close); a FlushNamespace
+	// call already in progress runs to completion.
+	Schedule(namespaces []databaseNamespace, mediator databaseMediator, cancel <-chan struct{})
+}
+
+// NaturalTickPolicy is the original M3 behavior: do nothing and let ticking
+// and flushing happen on its own course, so load is spread out across the
+// cluster over time.
+type NaturalTickPolicy struct{}
+
+// Schedule implements PostBootstrapFlushPolicy.
+func (p NaturalTickPolicy) Schedule(
+	namespaces []databaseNamespace,
+	mediator databaseMediator,
+	cancel <-chan struct{},
+) {
+}
+
+// ImmediateFlushPolicy ticks and flushes right away. It trades the load
+// spike described on PostBootstrapFlushPolicy for the fastest possible
+// durability of newly-bootstrapped data, and is best suited to clusters that
+// bootstrap nodes one at a time.
+type ImmediateFlushPolicy struct{}
+
+// Schedule implements PostBootstrapFlushPolicy. It runs on its own goroutine,
+// the same as the other policies, rather than inline: Schedule is invoked
+// from BootstrapWithContext while file ops are still disabled, and the
+// caller only re-enables them once Schedule (and the rest of the bootstrap
+// call) returns.
+func (p ImmediateFlushPolicy) Schedule(
+	namespaces []databaseNamespace,
+	mediator databaseMediator,
+	cancel <-chan struct{},
+) {
+	go func() {
+		for _, ns := range namespaces {
+			select {
+			case <-cancel:
+				return
+			default:
+			}
+			mediator.FlushNamespace(ns)
+		}
+	}()
+}
+
+// StaggeredFlushPolicy spreads the post-bootstrap flush out over a window of
+// Jitter, with the per-host delay deterministically derived from HostID so
+// that repeated runs on the same host stagger consistently but different
+// hosts in the cluster don't all flush at the same instant. At most
+// MaxConcurrentNamespaces namespaces are flushed at a time.
+type StaggeredFlushPolicy struct {
+	// HostID identifies this node and seeds the per-host jitter delay.
+	HostID string
+	// Jitter bounds how long after bootstrap completion the flush may start.
+	Jitter time.Duration
+	// MaxConcurrentNamespaces caps how many namespaces are flushed at once.
+	MaxConcurrentNamespaces int
+}
+
+// Schedule implements PostBootstrapFlushPolicy.
+func (p StaggeredFlushPolicy) Schedule(
+	namespaces []databaseNamespace,
+	mediator databaseMediator,
+	cancel <-chan struct{},
+) {
+	delay := p.hostJitterDelay()
+	maxConcurrent := p.MaxConcurrentNamespaces
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+
+	go func() {
+		select {
+		case <-cancel:
+			return
+		case <-time.After(delay):
+		}
+
+		sem := make(chan struct{}, maxConcurrent)
+		var wg sync.WaitGroup
+		for _, ns := range namespaces {
+			select {
+			case <-cancel:
+				// Let any already-started flushes finish, but start no more.
+				wg.Wait()
+				return
+			case sem <- struct{}{}:
+			}
+
+			ns := ns
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				mediator.FlushNamespace(ns)
+			}()
+		}
+		wg.Wait()
+	}()
+}
+
+// hostJitterDelay deterministically maps HostID into [0, Jitter) so that a
+// given host always staggers to roughly the same offset within the window.
+func (p StaggeredFlushPolicy) hostJitterDelay() time.Duration {
+	if p.Jitter <= 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(p.HostID))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	return time.Duration(r.Int63n(int64(p.Jitter)))
+}
+
+// ScheduledFlushPolicy triggers the post-bootstrap flush at a fixed wall
+// clock time, e.g. to align with a known low-traffic maintenance window. If
+// At has already passed by the time Schedule is invoked, the flush runs
+// immediately.
+type ScheduledFlushPolicy struct {
+	// At is the wall clock time the flush should run at.
+	At time.Time
+	// NowFn allows tests to control the current time; defaults to time.Now.
+	NowFn func() time.Time
+}
+
+// Schedule implements PostBootstrapFlushPolicy.
+func (p ScheduledFlushPolicy) Schedule(
+	namespaces []databaseNamespace,
+	mediator databaseMediator,
+	cancel <-chan struct{},
+) {
+	nowFn := p.NowFn
+	if nowFn == nil {
+		nowFn = time.Now
+	}
+
+	wait := p.At.Sub(nowFn())
+	if wait < 0 {
+		wait = 0
+	}
+
+	go func() {
+		select {
+		case <-cancel:
+			return
+		case <-time.After(wait):
+		}
+
+		for _, ns := range namespaces {
+			select {
+			case <-cancel:
+				return
+			default:
+			}
+			mediator.FlushNamespace(ns)
+		}
+	}()
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/m3db/m3/src/dbnode/storage/bootstrap"
+)
+
+// listenerDispatchQueueSize bounds the number of pending events queued per
+// listener so that a slow or stuck listener cannot apply backpressure to
+// bootstrapping. Events for that listener are dropped (and logged) once its
+// queue is full; other listeners are unaffected.
+const listenerDispatchQueueSize = 256
+
+// BootstrapRun describes a single invocation of the bootstrap process, passed
+// to BootstrapListener.OnBootstrapStart.
+type BootstrapRun struct {
+	// Attempt is the 1-indexed attempt number within the per-namespace retry
+	// loop in bootstrapManager.bootstrap. It restarts at 1 for each pending
+	// bootstrap that a single Bootstrap() call goes on to run.
+	Attempt int
+	// Start is the time the attempt began.
+	Start time.Time
+	// Namespaces are the IDs of the namespaces being bootstrapped this run.
+	Namespaces []string
+}
+
+// BootstrapListener can be registered on a databaseBootstrapManager to
+// observe bootstrap progress without polling IsBootstrapped(). For example,
+// the flush/snapshot manager can use OnNamespaceComplete to kick off a
+// targeted flush for just-bootstrapped shards instead of waiting for the
+// next natural tick.
+//
+// Each registered listener has its own bounded queue and worker goroutine, so
+// a slow listener only delays (and, once its queue fills, drops) its own
+// events; it has no effect on other listeners or on bootstrapping itself.
+type BootstrapListener interface {
+	// OnBootstrapStart is invoked when a bootstrap attempt begins.
+	OnBootstrapStart(run BootstrapRun)
+
+	// OnNamespaceStart is invoked before a namespace's shards are bootstrapped.
+	OnNamespaceStart(namespaceID string, shards []uint32)
+
+	// OnNamespaceComplete is invoked once a namespace's bootstrap attempt
+	// finishes, successfully or not.
+	OnNamespaceComplete(namespaceID string, result bootstrap.NamespaceResult, err error)
+
+	// OnBootstrapComplete is invoked once per Bootstrap() call, after all of
+	// its attempts and any pending bootstraps have finished, with the final
+	// error and the duration of the whole call.
+	OnBootstrapComplete(err error, duration time.Duration)
+}
+
+type listenerEvent func(BootstrapListener)
+
+// listenerWorker pairs a registered listener with its own bounded event
+// queue and the goroutine draining it.
+type listenerWorker struct {
+	listener BootstrapListener
+	events   chan listenerEvent
+}
+
+// RegisterListener registers l to receive bootstrap lifecycle events on its
+// own dedicated worker goroutine. It is safe to call concurrently with an
+// in-progress bootstrap.
+func (m *bootstrapManager) RegisterListener(l BootstrapListener) {
+	w := &listenerWorker{
+		listener: l,
+		events:   make(chan listenerEvent, listenerDispatchQueueSize),
+	}
+
+	m.Lock()
+	m.listeners = append(m.listeners, w)
+	m.Unlock()
+
+	go m.runListenerWorker(w)
+}
+
+// dispatch enqueues fn to be invoked against every registered listener, each
+// on its own worker goroutine. It never blocks: if a given listener's queue
+// is full, the event is dropped and logged for that listener only.
+func (m *bootstrapManager) dispatch(fn listenerEvent) {
+	m.RLock()
+	workers := m.listeners
+	m.RUnlock()
+
+	for _, w := range workers {
+		select {
+		case w.events <- fn:
+		default:
+			m.log.Warn("dropping bootstrap listener event, listener dispatch queue full")
+		}
+	}
+}
+
+// runListenerWorker drains a single listener's event queue until the
+// bootstrap manager is closed, so that a slow listener cannot stall any
+// other listener or bootstrapping itself.
+func (m *bootstrapManager) runListenerWorker(w *listenerWorker) {
+	for {
+		select {
+		case <-m.closeCh:
+			return
+		case fn := <-w.events:
+			fn(w.listener)
+		}
+	}
+}
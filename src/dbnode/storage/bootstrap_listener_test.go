@@ -0,0 +1,116 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3/src/dbnode/storage/bootstrap"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeBootstrapListener struct {
+	onBootstrapStart    func(run BootstrapRun)
+	onNamespaceStart    func(namespaceID string, shards []uint32)
+	onNamespaceComplete func(namespaceID string, result bootstrap.NamespaceResult, err error)
+	onBootstrapComplete func(err error, duration time.Duration)
+}
+
+func (f *fakeBootstrapListener) OnBootstrapStart(run BootstrapRun) {
+	if f.onBootstrapStart != nil {
+		f.onBootstrapStart(run)
+	}
+}
+
+func (f *fakeBootstrapListener) OnNamespaceStart(namespaceID string, shards []uint32) {
+	if f.onNamespaceStart != nil {
+		f.onNamespaceStart(namespaceID, shards)
+	}
+}
+
+func (f *fakeBootstrapListener) OnNamespaceComplete(namespaceID string, result bootstrap.NamespaceResult, err error) {
+	if f.onNamespaceComplete != nil {
+		f.onNamespaceComplete(namespaceID, result, err)
+	}
+}
+
+func (f *fakeBootstrapListener) OnBootstrapComplete(err error, duration time.Duration) {
+	if f.onBootstrapComplete != nil {
+		f.onBootstrapComplete(err, duration)
+	}
+}
+
+// TestBootstrapManagerDispatchDropsEventsOnFullQueue verifies that a slow
+// listener's queue filling up only drops that listener's own excess events
+// rather than blocking dispatch (and, transitively, bootstrapping itself).
+func TestBootstrapManagerDispatchDropsEventsOnFullQueue(t *testing.T) {
+	m := &bootstrapManager{
+		log:     zap.NewNop(),
+		closeCh: make(chan struct{}),
+	}
+	defer m.Close()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	var startedOnce sync.Once
+	var processed int32
+
+	listener := &fakeBootstrapListener{
+		onBootstrapComplete: func(error, time.Duration) {
+			startedOnce.Do(func() { close(started) })
+			<-block
+			atomic.AddInt32(&processed, 1)
+		},
+	}
+	m.RegisterListener(listener)
+
+	// This event is picked up by the listener's worker and blocks it,
+	// leaving the queue itself empty to fill below.
+	m.dispatch(func(l BootstrapListener) { l.OnBootstrapComplete(nil, 0) })
+	<-started
+
+	for i := 0; i < listenerDispatchQueueSize; i++ {
+		m.dispatch(func(l BootstrapListener) { l.OnBootstrapComplete(nil, 0) })
+	}
+
+	// The queue is now full; dispatching one more must drop it, not block.
+	dispatched := make(chan struct{})
+	go func() {
+		m.dispatch(func(l BootstrapListener) { l.OnBootstrapComplete(nil, 0) })
+		close(dispatched)
+	}()
+	select {
+	case <-dispatched:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked instead of dropping the event for a full listener queue")
+	}
+
+	close(block)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&processed) == int32(listenerDispatchQueueSize)+1
+	}, time.Second, time.Millisecond,
+		"expected the blocking event plus every queued event to run, with the overflow event dropped")
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/m3db/m3/src/x/retry"
+)
+
+const (
+	// defaultPeriodicBootstrapInterval is how often the background supervisor
+	// re-evaluates whether a bootstrap needs to be triggered.
+	defaultPeriodicBootstrapInterval = 10 * time.Minute
+
+	// defaultMinBootstrappedShardRatio means any owned shard that is not yet
+	// bootstrapped is enough to trigger a periodic re-bootstrap.
+	defaultMinBootstrappedShardRatio = 1.0
+
+	// defaultMaxNamespaceBootstrapRetries is how many times a single
+	// namespace may fail to bootstrap before the error is surfaced instead
+	// of retried.
+	defaultMaxNamespaceBootstrapRetries = 5
+)
+
+func defaultBootstrapRetryOptions() retry.Options {
+	return retry.NewOptions().
+		SetInitialBackoff(2 * time.Second).
+		SetBackoffFactor(2).
+		SetMaxBackoff(time.Minute).
+		SetMaxRetries(0). // retried indefinitely by the periodic supervisor
+		SetJitter(true)
+}
+
+// BootstrapManagerOptions are options for controlling the background,
+// self-healing periodic bootstrap loop run by the bootstrap manager.
+type BootstrapManagerOptions interface {
+	// SetPeriodicBootstrapEnabled sets whether the periodic bootstrap
+	// supervisor is enabled.
+	SetPeriodicBootstrapEnabled(value bool) BootstrapManagerOptions
+
+	// PeriodicBootstrapEnabled returns whether the periodic bootstrap
+	// supervisor is enabled.
+	PeriodicBootstrapEnabled() bool
+
+	// SetPeriodicBootstrapInterval sets the interval between periodic
+	// bootstrap checks.
+	SetPeriodicBootstrapInterval(value time.Duration) BootstrapManagerOptions
+
+	// PeriodicBootstrapInterval returns the interval between periodic
+	// bootstrap checks.
+	PeriodicBootstrapInterval() time.Duration
+
+	// SetMinBootstrappedShardRatio sets the minimum fraction of owned shards
+	// that must be bootstrapped; when the bootstrapped fraction drops below
+	// it, the periodic supervisor triggers a re-bootstrap.
+	SetMinBootstrappedShardRatio(value float64) BootstrapManagerOptions
+
+	// MinBootstrappedShardRatio returns the minimum bootstrapped shard ratio.
+	MinBootstrappedShardRatio() float64
+
+	// SetBootstrapRetryOptions sets the retry options used to back off
+	// between periodic bootstrap attempts that failed.
+	SetBootstrapRetryOptions(value retry.Options) BootstrapManagerOptions
+
+	// BootstrapRetryOptions returns the retry options used to back off
+	// between periodic bootstrap attempts that failed.
+	BootstrapRetryOptions() retry.Options
+
+	// SetMaxNamespaceBootstrapRetries sets how many times a single namespace
+	// may fail to bootstrap before the failure is surfaced as a hard error
+	// instead of being retried on the next attempt.
+	SetMaxNamespaceBootstrapRetries(value int) BootstrapManagerOptions
+
+	// MaxNamespaceBootstrapRetries returns the max per-namespace retries.
+	MaxNamespaceBootstrapRetries() int
+}
+
+type bootstrapManagerOptions struct {
+	periodicBootstrapEnabled     bool
+	periodicBootstrapInterval    time.Duration
+	minBootstrappedShardRatio    float64
+	bootstrapRetryOpts           retry.Options
+	maxNamespaceBootstrapRetries int
+}
+
+// NewBootstrapManagerOptions creates new BootstrapManagerOptions with
+// reasonable defaults for the periodic self-healing bootstrap loop.
+func NewBootstrapManagerOptions() BootstrapManagerOptions {
+	return &bootstrapManagerOptions{
+		periodicBootstrapEnabled:     true,
+		periodicBootstrapInterval:    defaultPeriodicBootstrapInterval,
+		minBootstrappedShardRatio:    defaultMinBootstrappedShardRatio,
+		bootstrapRetryOpts:           defaultBootstrapRetryOptions(),
+		maxNamespaceBootstrapRetries: defaultMaxNamespaceBootstrapRetries,
+	}
+}
+
+func (o *bootstrapManagerOptions) SetPeriodicBootstrapEnabled(value bool) BootstrapManagerOptions {
+	opts := *o
+	opts.periodicBootstrapEnabled = value
+	return &opts
+}
+
+func (o *bootstrapManagerOptions) PeriodicBootstrapEnabled() bool {
+	return o.periodicBootstrapEnabled
+}
+
+func (o *bootstrapManagerOptions) SetPeriodicBootstrapInterval(value time.Duration) BootstrapManagerOptions {
+	opts := *o
+	opts.periodicBootstrapInterval = value
+	return &opts
+}
+
+func (o *bootstrapManagerOptions) PeriodicBootstrapInterval() time.Duration {
+	return o.periodicBootstrapInterval
+}
+
+func (o *bootstrapManagerOptions) SetMinBootstrappedShardRatio(value float64) BootstrapManagerOptions {
+	opts := *o
+	opts.minBootstrappedShardRatio = value
+	return &opts
+}
+
+func (o *bootstrapManagerOptions) MinBootstrappedShardRatio() float64 {
+	return o.minBootstrappedShardRatio
+}
+
+func (o *bootstrapManagerOptions) SetBootstrapRetryOptions(value retry.Options) BootstrapManagerOptions {
+	opts := *o
+	opts.bootstrapRetryOpts = value
+	return &opts
+}
+
+func (o *bootstrapManagerOptions) BootstrapRetryOptions() retry.Options {
+	return o.bootstrapRetryOpts
+}
+
+func (o *bootstrapManagerOptions) SetMaxNamespaceBootstrapRetries(value int) BootstrapManagerOptions {
+	opts := *o
+	opts.maxNamespaceBootstrapRetries = value
+	return &opts
+}
+
+func (o *bootstrapManagerOptions) MaxNamespaceBootstrapRetries() int {
+	return o.maxNamespaceBootstrapRetries
+}
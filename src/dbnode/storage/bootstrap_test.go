@@ -0,0 +1,192 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3/src/dbnode/namespace"
+	"github.com/m3db/m3/src/dbnode/storage/bootstrap"
+	"github.com/m3db/m3/src/x/ident"
+	"github.com/m3db/m3/src/x/retry"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/zap"
+)
+
+// fakeDatabase is a minimal databaseNamespace-returning fake sufficient to
+// drive bootstrapManager's retry loop without the rest of the database.
+type fakeDatabase struct {
+	getOwnedNamespacesFn func() ([]databaseNamespace, error)
+}
+
+func (f *fakeDatabase) GetOwnedNamespaces() ([]databaseNamespace, error) {
+	return f.getOwnedNamespacesFn()
+}
+
+type fakeMediator struct{}
+
+func (f *fakeMediator) DisableFileOps() {}
+func (f *fakeMediator) EnableFileOps()  {}
+
+func (f *fakeMediator) FlushNamespace(databaseNamespace) error { return nil }
+
+type fakeProcess struct {
+	runFn func(ctx context.Context, start time.Time, namespaces []bootstrap.ProcessNamespace) (bootstrap.ProcessResult, error)
+}
+
+func (p fakeProcess) Run(
+	ctx context.Context,
+	start time.Time,
+	namespaces []bootstrap.ProcessNamespace,
+) (bootstrap.ProcessResult, error) {
+	return p.runFn(ctx, start, namespaces)
+}
+
+type fakeProcessProvider struct {
+	runFn func(ctx context.Context, start time.Time, namespaces []bootstrap.ProcessNamespace) (bootstrap.ProcessResult, error)
+}
+
+func (p *fakeProcessProvider) Provide(ctx context.Context) (bootstrap.Process, error) {
+	return fakeProcess{runFn: p.runFn}, nil
+}
+
+type fakeDatabaseShard struct{ id uint32 }
+
+func (f *fakeDatabaseShard) ID() uint32           { return f.id }
+func (f *fakeDatabaseShard) IsBootstrapped() bool { return false }
+
+type fakeDatabaseNamespace struct {
+	id           ident.ID
+	shards       []databaseShard
+	bootstrapErr error
+}
+
+func (f *fakeDatabaseNamespace) ID() ident.ID                    { return f.id }
+func (f *fakeDatabaseNamespace) Metadata() namespace.Metadata    { return nil }
+func (f *fakeDatabaseNamespace) GetOwnedShards() []databaseShard { return f.shards }
+
+func (f *fakeDatabaseNamespace) Bootstrap(bootstrap.NamespaceResult) error {
+	return f.bootstrapErr
+}
+
+// TestBootstrapWithContextCancelWithPendingLeavesConsistentState verifies
+// that cancelling ctx while another bootstrap has been enqueued (e.g. by a
+// reshard arriving mid-attempt) doesn't leave the manager stuck reporting
+// Bootstrapping forever.
+func TestBootstrapWithContextCancelWithPendingLeavesConsistentState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := &bootstrapManager{
+		log:           zap.NewNop(),
+		nowFn:         time.Now,
+		bootstrapOpts: NewBootstrapManagerOptions(),
+		mediator:      &fakeMediator{},
+		processProvider: &fakeProcessProvider{
+			runFn: func(context.Context, time.Time, []bootstrap.ProcessNamespace) (bootstrap.ProcessResult, error) {
+				return bootstrap.ProcessResult{Results: bootstrap.NewNamespaceResultsMap()}, nil
+			},
+		},
+		database: &fakeDatabase{
+			getOwnedNamespacesFn: func() ([]databaseNamespace, error) {
+				// Simulate a reshard enqueuing another bootstrap, then the
+				// database shutting down, while this attempt is in flight.
+				m.Lock()
+				m.hasPending = true
+				m.Unlock()
+				cancel()
+				return nil, nil
+			},
+		},
+		postBootstrapFlushPolicy: NaturalTickPolicy{},
+	}
+
+	err := m.BootstrapWithContext(ctx)
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.Canceled)
+
+	m.RLock()
+	state, hasPending := m.state, m.hasPending
+	m.RUnlock()
+	require.Equal(t, Bootstrapped, state, "manager must not be left in Bootstrapping forever")
+	require.False(t, hasPending)
+	require.True(t, m.IsBootstrapped())
+}
+
+// TestBootstrapRetryExhaustionAndPartialSuccess verifies that a namespace
+// that already succeeded is excluded from later attempts (rather than
+// re-run and possibly miscounted as a failure), and that a namespace which
+// exhausts its retries is surfaced as a permanent error as soon as it
+// exhausts, without waiting on an already-succeeded namespace.
+func TestBootstrapRetryExhaustionAndPartialSuccess(t *testing.T) {
+	good := &fakeDatabaseNamespace{
+		id:     ident.StringID("good"),
+		shards: []databaseShard{&fakeDatabaseShard{id: 0}},
+	}
+	bad := &fakeDatabaseNamespace{
+		id:           ident.StringID("bad"),
+		shards:       []databaseShard{&fakeDatabaseShard{id: 1}},
+		bootstrapErr: errors.New("bad namespace failed"),
+	}
+
+	var runTargetCounts []int
+	processProvider := &fakeProcessProvider{
+		runFn: func(_ context.Context, _ time.Time, targets []bootstrap.ProcessNamespace) (bootstrap.ProcessResult, error) {
+			runTargetCounts = append(runTargetCounts, len(targets))
+
+			results := bootstrap.NewNamespaceResultsMap()
+			results.Set(good.id, bootstrap.NamespaceResult{})
+			results.Set(bad.id, bootstrap.NamespaceResult{})
+			return bootstrap.ProcessResult{Results: results}, nil
+		},
+	}
+
+	m := &bootstrapManager{
+		log:             zap.NewNop(),
+		nowFn:           time.Now,
+		processProvider: processProvider,
+		database: &fakeDatabase{
+			getOwnedNamespacesFn: func() ([]databaseNamespace, error) {
+				return []databaseNamespace{good, bad}, nil
+			},
+		},
+		bootstrapOpts: NewBootstrapManagerOptions().
+			SetMaxNamespaceBootstrapRetries(2).
+			SetBootstrapRetryOptions(retry.NewOptions().
+				SetInitialBackoff(time.Millisecond).
+				SetMaxBackoff(time.Millisecond).
+				SetJitter(false)),
+		namespaceFailureCounters: map[string]tally.Counter{
+			"bad": tally.NoopScope.Counter("bootstrap-namespace-failures"),
+		},
+	}
+
+	err := m.bootstrap(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad")
+
+	require.Equal(t, []int{2, 1}, runTargetCounts,
+		"the second attempt must exclude the namespace that already succeeded on the first")
+}